@@ -0,0 +1,92 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"path"
+	"time"
+)
+
+// AuditFilter describes a set of criteria for narrowing down an
+// AuditEvent stream. The zero value of a field means "don't filter
+// on this criterion".
+type AuditFilter struct {
+	APIPathGlob     string        // Only match events whose APIPath matches this glob pattern
+	MinStatus       int           // Only match events with a StatusCode >= MinStatus
+	ClientIdentity  Identity      // Only match events from this ClientIdentity
+	Since           time.Time     // Only match events with a Timestamp at or after Since
+	MinResponseTime time.Duration // Only match events with a ResponseTime >= MinResponseTime
+}
+
+// Matches reports whether event satisfies every criterion set on f.
+func (f *AuditFilter) Matches(event AuditEvent) bool {
+	if f.APIPathGlob != "" {
+		if ok, err := path.Match(f.APIPathGlob, event.APIPath); err != nil || !ok {
+			return false
+		}
+	}
+	if f.MinStatus != 0 && event.StatusCode < f.MinStatus {
+		return false
+	}
+	if f.ClientIdentity != "" && event.ClientIdentity != f.ClientIdentity {
+		return false
+	}
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if event.ResponseTime < f.MinResponseTime {
+		return false
+	}
+	return true
+}
+
+// serverFilterer is implemented by AuditStream sources, such as the
+// one created by NewAuditEventSource, that can push an AuditFilter
+// down to the server instead of applying it client-side.
+type serverFilterer interface {
+	// SupportsServerFilter reports whether the server has advertised
+	// support for server-side audit filtering.
+	SupportsServerFilter() bool
+
+	// ApplyFilter configures the source to request filter from the
+	// server on its next (re-)connection.
+	ApplyFilter(filter AuditFilter)
+}
+
+// Filter returns a new AuditStream that only surfaces events from s
+// that match filter.
+//
+// filter is always applied client-side in Next, by skipping
+// non-matching events, so the result is correct immediately. If s
+// reads from a source that can push filters down - see
+// NewAuditEventSource - and its server has advertised support for
+// server-side filtering, filter is additionally pushed down to the
+// server as query parameters, to cut the firehose at the source; that
+// push-down only takes effect on the source's next (re-)connection,
+// which is why the client-side filter is kept rather than relied upon
+// as the sole mechanism.
+func (s *AuditStream) Filter(filter AuditFilter) *AuditStream {
+	s.mu.Lock()
+	event, err, closed := s.event, s.err, s.closed
+	s.mu.Unlock()
+
+	// Built up field by field, rather than with "f := *s", so that f
+	// gets its own zero-value mu instead of a copy of s.mu - copying a
+	// sync.Mutex is a bug even when, as here, it isn't held at the
+	// time.
+	f := &AuditStream{
+		codec:  s.codec,
+		closer: s.closer,
+		source: s.source,
+		filter: &filter,
+		event:  event,
+		err:    err,
+		closed: closed,
+	}
+	if neg, ok := s.source.(serverFilterer); ok {
+		neg.ApplyFilter(filter)
+	}
+	return f
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditFilterMatches(t *testing.T) {
+	base := AuditEvent{
+		Timestamp:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		APIPath:        "/v1/key/create/my-key",
+		ClientIdentity: Identity("abc123"),
+		StatusCode:     200,
+		ResponseTime:   50 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{name: "no criteria matches everything", filter: AuditFilter{}, want: true},
+		{name: "matching path glob", filter: AuditFilter{APIPathGlob: "/v1/key/create/*"}, want: true},
+		{name: "non-matching path glob", filter: AuditFilter{APIPathGlob: "/v1/key/delete/*"}, want: false},
+		{name: "invalid glob never matches", filter: AuditFilter{APIPathGlob: "["}, want: false},
+		{name: "status at minimum matches", filter: AuditFilter{MinStatus: 200}, want: true},
+		{name: "status below minimum", filter: AuditFilter{MinStatus: 500}, want: false},
+		{name: "matching client identity", filter: AuditFilter{ClientIdentity: Identity("abc123")}, want: true},
+		{name: "non-matching client identity", filter: AuditFilter{ClientIdentity: Identity("other")}, want: false},
+		{name: "event at or after since", filter: AuditFilter{Since: base.Timestamp}, want: true},
+		{name: "event before since", filter: AuditFilter{Since: base.Timestamp.Add(time.Second)}, want: false},
+		{name: "response time at minimum", filter: AuditFilter{MinResponseTime: 50 * time.Millisecond}, want: true},
+		{name: "response time below minimum", filter: AuditFilter{MinResponseTime: 100 * time.Millisecond}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Matches(base); got != test.want {
+				t.Errorf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBORCodec is a Codec that decodes and encodes events as a sequence
+// of CBOR (RFC 8949) values. Compared to NDJSONCodec, it avoids the
+// allocation overhead of streaming JSON decoding and cuts the number
+// of bytes transferred, which matters when tailing high-volume audit
+// logs.
+type CBORCodec struct {
+	dec *cbor.Decoder
+	enc *cbor.Encoder
+}
+
+// NewCBORCodec returns a Codec that decodes CBOR values from r, if r
+// is non-nil, and encodes CBOR values to w, if w is non-nil.
+func NewCBORCodec(r io.Reader, w io.Writer) *CBORCodec {
+	c := new(CBORCodec)
+	if r != nil {
+		c.dec = cbor.NewDecoder(r)
+	}
+	if w != nil {
+		c.enc = cbor.NewEncoder(w)
+	}
+	return c
+}
+
+// Decode decodes the next CBOR value into v.
+func (c *CBORCodec) Decode(v any) error { return c.dec.Decode(v) }
+
+// Encode encodes v as a CBOR value.
+func (c *CBORCodec) Encode(v any) error { return c.enc.Encode(v) }
+
+// ContentType returns "application/cbor-seq".
+func (c *CBORCodec) ContentType() string { return "application/cbor-seq" }
@@ -0,0 +1,74 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// errProtobufCodecUnsupported is the error reported, e.g. by Close, by
+// an ErrorStream or AuditStream that NewErrorStreamWithCodec or
+// NewAuditStreamWithCodec built from a *ProtobufCodec: ErrorEvent
+// and AuditEvent are plain structs, not generated proto.Message
+// types, so there is nothing for ProtobufCodec to decode into.
+var errProtobufCodecUnsupported = errors.New("kes: ProtobufCodec requires a generated proto.Message type; ErrorEvent/AuditEvent are not supported")
+
+// Codec is the wire encoding used by ErrorStream and AuditStream to
+// decode events from, and encode events to, an underlying transport.
+//
+// Implementations must be able to decode a sequence of values written
+// back-to-back, the same way json.Decoder.Decode can be called
+// repeatedly on a stream of concatenated JSON values.
+//
+// CBORCodec and ProtobufCodec pull in github.com/fxamacker/cbor/v2
+// and google.golang.org/protobuf respectively; this tree predates a
+// go.mod for the module, so neither dependency is pinned anywhere yet
+// - that still needs a real `go mod init && go mod tidy` against a
+// live module proxy before either codec can build.
+type Codec interface {
+	// Decode decodes the next value from the stream into v.
+	// It returns io.EOF once the stream is exhausted.
+	Decode(v any) error
+
+	// Encode encodes v to the stream.
+	Encode(v any) error
+
+	// ContentType returns the MIME type identifying this Codec on
+	// the wire, e.g. for content negotiation via an Accept header.
+	ContentType() string
+}
+
+// NDJSONCodec is the default Codec used by NewErrorStream and
+// NewAuditStream. It decodes and encodes one JSON value per event -
+// newline-delimited JSON (NDJSON) - the wire format KES servers have
+// always used for the error and audit log endpoints.
+type NDJSONCodec struct {
+	dec *json.Decoder
+	enc *json.Encoder
+}
+
+// NewNDJSONCodec returns a Codec that decodes from r, if r is
+// non-nil, and encodes to w, if w is non-nil.
+func NewNDJSONCodec(r io.Reader, w io.Writer) *NDJSONCodec {
+	c := new(NDJSONCodec)
+	if r != nil {
+		c.dec = json.NewDecoder(r)
+	}
+	if w != nil {
+		c.enc = json.NewEncoder(w)
+	}
+	return c
+}
+
+// Decode decodes the next JSON value into v.
+func (c *NDJSONCodec) Decode(v any) error { return c.dec.Decode(v) }
+
+// Encode encodes v as a JSON value, followed by a newline.
+func (c *NDJSONCodec) Encode(v any) error { return c.enc.Encode(v) }
+
+// ContentType returns "application/x-ndjson".
+func (c *NDJSONCodec) ContentType() string { return "application/x-ndjson" }
@@ -0,0 +1,79 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNDJSONCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	want := ErrorEvent{Message: "boom"}
+	if err := NewNDJSONCodec(nil, &buf).Encode(want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got ErrorEvent
+	if err := NewNDJSONCodec(&buf, nil).Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if ct := NewNDJSONCodec(nil, nil).ContentType(); ct != "application/x-ndjson" {
+		t.Errorf("ContentType() = %q, want application/x-ndjson", ct)
+	}
+}
+
+func TestCBORCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	want := ErrorEvent{Message: "boom"}
+	if err := NewCBORCodec(nil, &buf).Encode(want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got ErrorEvent
+	if err := NewCBORCodec(&buf, nil).Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if ct := NewCBORCodec(nil, nil).ContentType(); ct != "application/cbor-seq" {
+		t.Errorf("ContentType() = %q, want application/cbor-seq", ct)
+	}
+}
+
+// TestProtobufCodecRejectsNonProtoMessage covers the path ProtobufCodec
+// actually exercises today: ErrorEvent/AuditEvent aren't generated
+// proto.Message types, so Encode/Decode must fail descriptively rather
+// than panic. A true wire round-trip needs a generated proto.Message,
+// which this tree has none of yet.
+func TestProtobufCodecRejectsNonProtoMessage(t *testing.T) {
+	enc := NewProtobufCodec(nil, new(bytes.Buffer))
+	if err := enc.Encode(ErrorEvent{Message: "boom"}); err == nil {
+		t.Fatal("Encode succeeded for a value that does not implement proto.Message")
+	}
+
+	dec := NewProtobufCodec(bytes.NewReader(nil), nil)
+	var event ErrorEvent
+	if err := dec.Decode(&event); err == nil {
+		t.Fatal("Decode succeeded for a value that does not implement proto.Message")
+	}
+}
+
+func TestNewErrorStreamWithCodecRejectsProtobufCodec(t *testing.T) {
+	codec := NewProtobufCodec(bytes.NewReader(nil), nil)
+	stream := NewErrorStreamWithCodec(codec, nil)
+
+	if stream.Next() {
+		t.Fatal("Next() succeeded on a stream built from an unsupported ProtobufCodec")
+	}
+	if err := stream.Close(); !errors.Is(err, errProtobufCodecUnsupported) {
+		t.Fatalf("Close() = %v, want errProtobufCodecUnsupported", err)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSinkConfig configures a rotating file sink.
+type FileSinkConfig struct {
+	Dir    string // Directory the active and rotated log files are written to
+	Prefix string // Base name of the active log file: "<Prefix>.log"
+
+	MaxSize int64         // A write first rotates the active file if it has reached MaxSize bytes. Zero disables size-based rotation.
+	MaxAge  time.Duration // A write first rotates the active file if MaxAge has passed since the sink opened it. Zero disables age-based rotation.
+}
+
+// fileSink implements the rotate-and-gzip file sink shared by
+// FileAuditSink and FileErrorSink.
+type fileSink[T any] struct {
+	cfg FileSinkConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newFileSink[T any](cfg FileSinkConfig) (*fileSink[T], error) {
+	s := &fileSink[T]{cfg: cfg}
+	if err := s.openLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink[T]) activePath() string {
+	return filepath.Join(s.cfg.Dir, s.cfg.Prefix+".log")
+}
+
+func (s *fileSink[T]) openLocked() error {
+	f, err := os.OpenFile(s.activePath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	s.openedAt = time.Now()
+	return nil
+}
+
+func (s *fileSink[T]) write(event T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.needsRotateLocked() {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	cw := countWriter{W: s.file}
+	if err := json.NewEncoder(&cw).Encode(event); err != nil {
+		return err
+	}
+	s.size += cw.N
+	return nil
+}
+
+func (s *fileSink[T]) needsRotateLocked() bool {
+	return (s.cfg.MaxSize > 0 && s.size >= s.cfg.MaxSize) ||
+		(s.cfg.MaxAge > 0 && time.Since(s.openedAt) >= s.cfg.MaxAge)
+}
+
+func (s *fileSink[T]) rotateLocked() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := filepath.Join(s.cfg.Dir, fmt.Sprintf("%s-%s.log", s.cfg.Prefix, time.Now().UTC().Format("20060102T150405.000000000Z")))
+	if err := os.Rename(s.activePath(), rotated); err != nil {
+		return err
+	}
+	if err := gzipFile(rotated); err != nil {
+		return err
+	}
+	return s.openLocked()
+}
+
+func (s *fileSink[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// gzipFile compresses path in place as path+".gz" and removes path.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err != nil {
+		gw.Close()
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// FileAuditSink is an AuditSink that appends NDJSON-encoded
+// AuditEvents to a file, rotating - and gzip-compressing the rotated
+// file - by size and/or age.
+type FileAuditSink struct {
+	*fileSink[AuditEvent]
+}
+
+// NewFileAuditSink returns a FileAuditSink writing into cfg.Dir.
+func NewFileAuditSink(cfg FileSinkConfig) (*FileAuditSink, error) {
+	s, err := newFileSink[AuditEvent](cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{s}, nil
+}
+
+// Write appends event to the active log file.
+func (s *FileAuditSink) Write(ctx context.Context, event AuditEvent) error { return s.write(event) }
+
+// FileErrorSink is an ErrorSink that appends NDJSON-encoded
+// ErrorEvents to a file, rotating - and gzip-compressing the rotated
+// file - by size and/or age.
+type FileErrorSink struct {
+	*fileSink[ErrorEvent]
+}
+
+// NewFileErrorSink returns a FileErrorSink writing into cfg.Dir.
+func NewFileErrorSink(cfg FileSinkConfig) (*FileErrorSink, error) {
+	s, err := newFileSink[ErrorEvent](cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FileErrorSink{s}, nil
+}
+
+// Write appends event to the active log file.
+func (s *FileErrorSink) Write(ctx context.Context, event ErrorEvent) error { return s.write(event) }
@@ -0,0 +1,85 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSinkRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	cfg := FileSinkConfig{
+		Dir:     dir,
+		Prefix:  "test",
+		MaxSize: 10, // small enough that a single event forces the next write to rotate
+	}
+	s, err := newFileSink[ErrorEvent](cfg)
+	if err != nil {
+		t.Fatalf("newFileSink: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.write(ErrorEvent{Message: "first"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := s.write(ErrorEvent{Message: "second"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+
+	var rotated []string
+	var active bool
+	for _, e := range entries {
+		switch {
+		case e.Name() == "test.log":
+			active = true
+		case filepath.Ext(e.Name()) == ".gz":
+			rotated = append(rotated, e.Name())
+		}
+	}
+	if !active {
+		t.Error("active log file test.log is missing")
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("got %d rotated files, want 1: %v", len(rotated), rotated)
+	}
+
+	content, err := readGzipFile(filepath.Join(dir, rotated[0]))
+	if err != nil {
+		t.Fatalf("reading rotated file: %v", err)
+	}
+	if !strings.Contains(content, "first") {
+		t.Errorf("rotated file does not contain the first event: %q", content)
+	}
+	if strings.Contains(content, "second") {
+		t.Errorf("rotated file unexpectedly contains the second event: %q", content)
+	}
+}
+
+func readGzipFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer gr.Close()
+
+	content, err := io.ReadAll(gr)
+	return string(content), err
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy controls what an EventHub does when a subscriber
+// cannot keep up with the upstream event rate and its buffered
+// channel is full.
+type OverflowPolicy struct {
+	kind     overflowKind
+	blockFor time.Duration
+}
+
+type overflowKind uint8
+
+const (
+	dropOldestPolicy overflowKind = iota
+	dropNewestPolicy
+	blockUpToPolicy
+)
+
+// DropOldest discards the oldest buffered event to make room for the
+// incoming one.
+var DropOldest = OverflowPolicy{kind: dropOldestPolicy}
+
+// DropNewest discards the incoming event, keeping what is already
+// buffered.
+var DropNewest = OverflowPolicy{kind: dropNewestPolicy}
+
+// BlockUpTo blocks the hub for up to d, waiting for room in a slow
+// subscriber's buffer. If d elapses before room frees up, the event
+// is dropped and the subscriber is unregistered - left unchecked, a
+// persistently slow subscriber would otherwise stall delivery to
+// every other subscriber.
+func BlockUpTo(d time.Duration) OverflowPolicy {
+	return OverflowPolicy{kind: blockUpToPolicy, blockFor: d}
+}
+
+// EventHub fans a single upstream event stream out to any number of
+// subscribers, so that multiple consumers within the same process -
+// dashboards, forwarders, alerters - can share one upstream
+// connection instead of each opening their own.
+//
+// Create one with NewAuditEventHub or NewErrorEventHub.
+type EventHub[T any] struct {
+	bufferSize  int
+	policy      OverflowPolicy
+	closeSource func() error
+
+	mu    sync.Mutex
+	subs  map[*hubSub[T]]struct{}
+	index map[<-chan T]*hubSub[T]
+
+	done chan struct{}
+}
+
+type hubSub[T any] struct {
+	ch    chan T
+	drops atomic.Uint64
+}
+
+// NewAuditEventHub returns an EventHub that pumps AuditEvents from
+// source and fans them out to subscribers. source is owned by the
+// hub: closing the hub closes source.
+func NewAuditEventHub(source *AuditStream, bufferSize int, policy OverflowPolicy) *EventHub[AuditEvent] {
+	h := newEventHub[AuditEvent](bufferSize, policy, source.Close)
+	go h.run(source.Next, source.Event)
+	return h
+}
+
+// NewErrorEventHub returns an EventHub that pumps ErrorEvents from
+// source and fans them out to subscribers. source is owned by the
+// hub: closing the hub closes source.
+func NewErrorEventHub(source *ErrorStream, bufferSize int, policy OverflowPolicy) *EventHub[ErrorEvent] {
+	h := newEventHub[ErrorEvent](bufferSize, policy, source.Close)
+	go h.run(source.Next, source.Event)
+	return h
+}
+
+func newEventHub[T any](bufferSize int, policy OverflowPolicy, closeSource func() error) *EventHub[T] {
+	return &EventHub[T]{
+		bufferSize:  bufferSize,
+		policy:      policy,
+		closeSource: closeSource,
+		subs:        make(map[*hubSub[T]]struct{}),
+		index:       make(map[<-chan T]*hubSub[T]),
+		done:        make(chan struct{}),
+	}
+}
+
+// run is the hub's single pump goroutine: it advances the upstream
+// source via next and fans the resulting event, read via event, out
+// to every live subscriber - until the source is exhausted.
+func (h *EventHub[T]) run(next func() bool, event func() T) {
+	defer close(h.done)
+
+	for next() {
+		h.broadcast(event())
+	}
+}
+
+func (h *EventHub[T]) broadcast(event T) {
+	h.mu.Lock()
+	subs := make([]*hubSub[T], 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	h.mu.Unlock()
+
+	for _, sub := range subs {
+		h.deliver(sub, event)
+	}
+}
+
+// deliver sends event to sub, applying the hub's OverflowPolicy if
+// sub's buffer is currently full.
+func (h *EventHub[T]) deliver(sub *hubSub[T], event T) {
+	select {
+	case sub.ch <- event:
+		return
+	default:
+	}
+
+	switch h.policy.kind {
+	case dropNewestPolicy:
+		sub.drops.Add(1)
+	case dropOldestPolicy:
+		select {
+		case <-sub.ch:
+			sub.drops.Add(1)
+		default:
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.drops.Add(1)
+		}
+	case blockUpToPolicy:
+		timer := time.NewTimer(h.policy.blockFor)
+		defer timer.Stop()
+		select {
+		case sub.ch <- event:
+		case <-timer.C:
+			sub.drops.Add(1)
+			h.unregister(sub)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber and returns the channel it
+// will receive events on. The subscriber is unregistered - and no
+// longer considered by the hub's OverflowPolicy or Dropped - once ctx
+// is done or the hub itself is closed. Subscribe does not close the
+// returned channel; callers should stop reading from it once their
+// own ctx is done.
+func (h *EventHub[T]) Subscribe(ctx context.Context) <-chan T {
+	sub := &hubSub[T]{ch: make(chan T, h.bufferSize)}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.index[sub.ch] = sub
+	h.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-h.done:
+		}
+		h.unregister(sub)
+	}()
+	return sub.ch
+}
+
+func (h *EventHub[T]) unregister(sub *hubSub[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, sub)
+	delete(h.index, sub.ch)
+}
+
+// Dropped returns the number of events dropped for the subscription
+// identified by ch, the channel returned from Subscribe. It returns 0
+// once ch is no longer a live subscription of h.
+func (h *EventHub[T]) Dropped(ch <-chan T) uint64 {
+	h.mu.Lock()
+	sub, ok := h.index[ch]
+	h.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	return sub.drops.Load()
+}
+
+// Close stops the hub by closing its upstream source, which causes
+// the pump goroutine to exit once the source reports no more events,
+// unregistering all subscribers in the process.
+func (h *EventHub[T]) Close() error {
+	return h.closeSource()
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestEventHubBroadcastDelivers(t *testing.T) {
+	h := newEventHub[int](1, DropNewest, func() error { return nil })
+	ch := h.Subscribe(context.Background())
+
+	h.broadcast(42)
+
+	if got := <-ch; got != 42 {
+		t.Fatalf("got %d, want 42", got)
+	}
+}
+
+func TestEventHubDropNewest(t *testing.T) {
+	h := newEventHub[int](1, DropNewest, func() error { return nil })
+	ch := h.Subscribe(context.Background())
+
+	h.broadcast(1) // fills the only buffer slot
+	h.broadcast(2) // buffer full: DropNewest discards this one
+
+	if got := <-ch; got != 1 {
+		t.Fatalf("got %d, want 1 (the event that was already buffered)", got)
+	}
+	if n := h.Dropped(ch); n != 1 {
+		t.Fatalf("Dropped() = %d, want 1", n)
+	}
+}
+
+func TestEventHubDropOldestKeepsNewest(t *testing.T) {
+	h := newEventHub[int](1, DropOldest, func() error { return nil })
+	ch := h.Subscribe(context.Background())
+
+	h.broadcast(1) // fills the only buffer slot
+	h.broadcast(2) // buffer full: DropOldest evicts 1 to make room for 2
+
+	if got := <-ch; got != 2 {
+		t.Fatalf("got %d, want 2 (the newest event)", got)
+	}
+}
+
+func TestEventHubBlockUpToUnregistersSlowSubscriber(t *testing.T) {
+	h := newEventHub[int](1, BlockUpTo(10*time.Millisecond), func() error { return nil })
+	ch := h.Subscribe(context.Background())
+
+	h.broadcast(1) // fills the only buffer slot; never read
+	h.broadcast(2) // blocks up to 10ms for room, then drops the event and unregisters
+
+	start := time.Now()
+	h.broadcast(3) // the subscriber is gone by now, so this must return immediately
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Fatalf("broadcast took %v after the slow subscriber should have been unregistered", elapsed)
+	}
+
+	if got := <-ch; got != 1 {
+		t.Fatalf("got %d, want 1 (the only event that was ever buffered)", got)
+	}
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected value %d on a channel whose subscriber should be unregistered", v)
+	default:
+	}
+	if n := h.Dropped(ch); n != 0 {
+		t.Fatalf("Dropped() = %d, want 0 once the subscriber is no longer live", n)
+	}
+}
+
+// TestEventHubCloseRacesPumpGoroutine reproduces an EventHub's
+// ordinary lifecycle - create it, let it pump, Close it while the
+// pump goroutine may still be inside source.Next() - which used to
+// race on the source's own unsynchronized closed/err fields. Run with
+// `go test -race`.
+func TestEventHubCloseRacesPumpGoroutine(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		pr, pw := io.Pipe()
+		go func() {
+			enc := json.NewEncoder(pw)
+			for enc.Encode(ErrorEvent{Message: "x"}) == nil {
+			}
+		}()
+
+		hub := NewErrorEventHub(NewErrorStream(pr), 1, DropNewest)
+		hub.Subscribe(context.Background())
+
+		hub.Close()
+		pw.Close()
+	}
+}
+
+func TestEventHubSubscribeUnregistersOnContextCancel(t *testing.T) {
+	h := newEventHub[int](1, DropNewest, func() error { return nil })
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := h.Subscribe(ctx)
+	cancel()
+
+	deadline := time.After(time.Second)
+	for {
+		h.mu.Lock()
+		_, live := h.index[ch]
+		h.mu.Unlock()
+		if !live {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatal("subscriber was not unregistered after its context was cancelled")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
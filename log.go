@@ -5,10 +5,13 @@
 package kes
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -19,13 +22,28 @@ type ErrorEvent struct {
 }
 
 // NewErrorStream returns a new ErrorStream that
-// reads from r.
+// reads NDJSON-encoded ErrorEvents from r.
 func NewErrorStream(r io.Reader) *ErrorStream {
+	closer, _ := r.(io.Closer)
+	return NewErrorStreamWithCodec(NewNDJSONCodec(r, nil), closer)
+}
+
+// NewErrorStreamWithCodec returns a new ErrorStream that decodes
+// ErrorEvents using codec instead of the default NDJSON encoding.
+// If closer is non-nil, closing the returned ErrorStream closes closer.
+//
+// codec must decode into the plain ErrorEvent wire struct - a
+// *ProtobufCodec, which requires a generated proto.Message, is not
+// currently supported and causes the returned ErrorStream to fail
+// immediately with a descriptive error.
+func NewErrorStreamWithCodec(codec Codec, closer io.Closer) *ErrorStream {
 	s := &ErrorStream{
-		decoder: json.NewDecoder(r),
+		codec:  codec,
+		closer: closer,
 	}
-	if closer, ok := r.(io.Closer); ok {
-		s.closer = closer
+	if _, ok := codec.(*ProtobufCodec); ok {
+		s.err = errProtobufCodecUnsupported
+		s.closed = true
 	}
 	return s
 }
@@ -33,20 +51,44 @@ func NewErrorStream(r io.Reader) *ErrorStream {
 // ErrorStream iterates over a stream of ErrorEvents.
 // Close the ErrorStream to release associated resources.
 type ErrorStream struct {
-	decoder *json.Decoder
-	closer  io.Closer
+	codec  Codec
+	closer io.Closer
+	source eventSource
 
+	// mu guards event/err/closed. NextContext/WriteToContext run fn
+	// (Next/WriteTo) in the calling goroutine while a second goroutine
+	// races ctx.Done() and may call Close concurrently - without a
+	// lock, that's a data race on these fields.
+	mu     sync.Mutex
 	event  ErrorEvent
 	err    error
 	closed bool
 }
 
+// LastEventID returns the ID of the last event received over an SSE
+// event source, as created by NewErrorEventSource. It returns the
+// empty string if s does not read from an SSE event source.
+func (s *ErrorStream) LastEventID() string {
+	if s.source == nil {
+		return ""
+	}
+	return s.source.LastEventID()
+}
+
 // Event returns the most recent ErrorEvent, generated by Next.
-func (s *ErrorStream) Event() ErrorEvent { return s.event }
+func (s *ErrorStream) Event() ErrorEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.event
+}
 
 // Message returns the current error message or the ErrorEvent.
 // It is a short-hand for Event().Message.
-func (s *ErrorStream) Message() string { return s.event.Message }
+func (s *ErrorStream) Message() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.event.Message
+}
 
 // Next advances the stream to the next ErrorEvent and
 // returns true if there is another one. It returns
@@ -56,20 +98,27 @@ func (s *ErrorStream) Next() bool {
 	type Response struct {
 		Message string `json:"message"`
 	}
+	s.mu.Lock()
 	if s.err != nil || s.closed {
+		s.mu.Unlock()
 		return false
 	}
+	s.mu.Unlock()
 
 	var resp Response
-	if err := s.decoder.Decode(&resp); err != nil {
+	if err := s.codec.Decode(&resp); err != nil {
+		s.mu.Lock()
 		if errors.Is(err, io.EOF) {
-			s.err = s.Close()
+			s.err = s.closeLocked()
 		} else {
 			s.err = err
 		}
+		s.mu.Unlock()
 		return false
 	}
+	s.mu.Lock()
 	s.event = ErrorEvent(resp)
+	s.mu.Unlock()
 	return true
 }
 
@@ -85,24 +134,75 @@ func (s *ErrorStream) WriteTo(w io.Writer) (int64, error) {
 	encoder := json.NewEncoder(&cw)
 	for {
 		var resp Response
-		if err := s.decoder.Decode(&resp); err != nil {
+		if err := s.codec.Decode(&resp); err != nil {
+			s.mu.Lock()
 			if errors.Is(err, io.EOF) {
-				s.err = s.Close()
+				s.err = s.closeLocked()
 			} else {
 				s.err = err
 			}
-			return cw.N, s.err
+			retErr := s.err
+			s.mu.Unlock()
+			return cw.N, retErr
 		}
 		if err := encoder.Encode(resp); err != nil {
+			s.mu.Lock()
 			s.err = err
+			s.mu.Unlock()
 			return cw.N, err
 		}
 	}
 }
 
+// NextContext behaves like Next but also unblocks and returns false
+// as soon as ctx is done, closing the ErrorStream in the process.
+// Once ctx is done, subsequent calls to Next or NextContext return
+// false instead of blocking or panicking.
+func (s *ErrorStream) NextContext(ctx context.Context) bool {
+	s.mu.Lock()
+	if s.closed || s.err != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Unlock()
+
+	var ok bool
+	if ctxDone(ctx, s.Close, func() bool { ok = s.Next(); return ok }) {
+		s.mu.Lock()
+		s.err = fmt.Errorf("kes: error stream closed: %w", ctx.Err())
+		s.mu.Unlock()
+		return false
+	}
+	return ok
+}
+
+// WriteToContext behaves like WriteTo but also unblocks and returns
+// as soon as ctx is done, closing the ErrorStream in the process.
+func (s *ErrorStream) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	var (
+		n   int64
+		err error
+	)
+	if ctxDone(ctx, s.Close, func() bool { n, err = s.WriteTo(w); return err == nil }) {
+		s.mu.Lock()
+		s.err = fmt.Errorf("kes: error stream closed: %w", ctx.Err())
+		err = s.err
+		s.mu.Unlock()
+		return n, err
+	}
+	return n, err
+}
+
 // Close closes the ErrorStream and releases
 // any associated resources.
 func (s *ErrorStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
+
+// closeLocked is Close's body, for callers that already hold s.mu.
+func (s *ErrorStream) closeLocked() error {
 	if !s.closed {
 		s.closed = true
 
@@ -131,13 +231,28 @@ type AuditEvent struct {
 }
 
 // NewAuditStream returns a new AuditStream that
-// reads from r.
+// reads NDJSON-encoded AuditEvents from r.
 func NewAuditStream(r io.Reader) *AuditStream {
+	closer, _ := r.(io.Closer)
+	return NewAuditStreamWithCodec(NewNDJSONCodec(r, nil), closer)
+}
+
+// NewAuditStreamWithCodec returns a new AuditStream that decodes
+// AuditEvents using codec instead of the default NDJSON encoding.
+// If closer is non-nil, closing the returned AuditStream closes closer.
+//
+// codec must decode into the plain AuditEvent wire struct - a
+// *ProtobufCodec, which requires a generated proto.Message, is not
+// currently supported and causes the returned AuditStream to fail
+// immediately with a descriptive error.
+func NewAuditStreamWithCodec(codec Codec, closer io.Closer) *AuditStream {
 	s := &AuditStream{
-		decoder: json.NewDecoder(r),
+		codec:  codec,
+		closer: closer,
 	}
-	if closer, ok := r.(io.Closer); ok {
-		s.closer = closer
+	if _, ok := codec.(*ProtobufCodec); ok {
+		s.err = errProtobufCodecUnsupported
+		s.closed = true
 	}
 	return s
 }
@@ -145,16 +260,37 @@ func NewAuditStream(r io.Reader) *AuditStream {
 // AuditStream iterates over a stream of AuditEvents.
 // Close the AuditStream to release associated resources.
 type AuditStream struct {
-	decoder *json.Decoder
-	closer  io.Closer
+	codec  Codec
+	closer io.Closer
+	source eventSource
+	filter *AuditFilter
 
+	// mu guards event/err/closed. NextContext/WriteToContext run fn
+	// (Next/WriteTo) in the calling goroutine while a second goroutine
+	// races ctx.Done() and may call Close concurrently - without a
+	// lock, that's a data race on these fields.
+	mu     sync.Mutex
 	event  AuditEvent
 	err    error
 	closed bool
 }
 
+// LastEventID returns the ID of the last event received over an SSE
+// event source, as created by NewAuditEventSource. It returns the
+// empty string if s does not read from an SSE event source.
+func (s *AuditStream) LastEventID() string {
+	if s.source == nil {
+		return ""
+	}
+	return s.source.LastEventID()
+}
+
 // Event returns the most recent AuditEvent, generated by Next.
-func (s *AuditStream) Event() AuditEvent { return s.event }
+func (s *AuditStream) Event() AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.event
+}
 
 // Next advances the stream to the next AuditEvent and
 // returns true if there is another one. It returns
@@ -173,27 +309,41 @@ func (s *AuditStream) Next() bool {
 			Time       time.Duration `json:"time"`
 		} `json:"response"`
 	}
+	s.mu.Lock()
 	if s.closed || s.err != nil {
+		s.mu.Unlock()
 		return false
 	}
-	var resp Response
-	if err := s.decoder.Decode(&resp); err != nil {
-		if errors.Is(err, io.EOF) {
-			s.err = s.Close()
-		} else {
-			s.err = err
+	s.mu.Unlock()
+
+	for {
+		var resp Response
+		if err := s.codec.Decode(&resp); err != nil {
+			s.mu.Lock()
+			if errors.Is(err, io.EOF) {
+				s.err = s.closeLocked()
+			} else {
+				s.err = err
+			}
+			s.mu.Unlock()
+			return false
 		}
-		return false
-	}
-	s.event = AuditEvent{
-		Timestamp:      resp.Timestamp,
-		APIPath:        resp.Request.APIPath,
-		ClientIP:       resp.Request.IP,
-		ClientIdentity: resp.Request.Identity,
-		StatusCode:     resp.Response.StatusCode,
-		ResponseTime:   resp.Response.Time,
+		event := AuditEvent{
+			Timestamp:      resp.Timestamp,
+			APIPath:        resp.Request.APIPath,
+			ClientIP:       resp.Request.IP,
+			ClientIdentity: resp.Request.Identity,
+			StatusCode:     resp.Response.StatusCode,
+			ResponseTime:   resp.Response.Time,
+		}
+		if s.filter != nil && !s.filter.Matches(event) {
+			continue
+		}
+		s.mu.Lock()
+		s.event = event
+		s.mu.Unlock()
+		return true
 	}
-	return true
 }
 
 // WriteTo writes the entire AuditEvent stream to w.
@@ -217,24 +367,75 @@ func (s *AuditStream) WriteTo(w io.Writer) (int64, error) {
 	encoder := json.NewEncoder(&cw)
 	for {
 		var resp Response
-		if err := s.decoder.Decode(&resp); err != nil {
+		if err := s.codec.Decode(&resp); err != nil {
+			s.mu.Lock()
 			if errors.Is(err, io.EOF) {
-				s.err = s.Close()
+				s.err = s.closeLocked()
 			} else {
 				s.err = err
 			}
-			return cw.N, s.err
+			retErr := s.err
+			s.mu.Unlock()
+			return cw.N, retErr
 		}
 		if err := encoder.Encode(resp); err != nil {
+			s.mu.Lock()
 			s.err = err
+			s.mu.Unlock()
 			return cw.N, err
 		}
 	}
 }
 
+// NextContext behaves like Next but also unblocks and returns false
+// as soon as ctx is done, closing the AuditStream in the process.
+// Once ctx is done, subsequent calls to Next or NextContext return
+// false instead of blocking or panicking.
+func (s *AuditStream) NextContext(ctx context.Context) bool {
+	s.mu.Lock()
+	if s.closed || s.err != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Unlock()
+
+	var ok bool
+	if ctxDone(ctx, s.Close, func() bool { ok = s.Next(); return ok }) {
+		s.mu.Lock()
+		s.err = fmt.Errorf("kes: audit stream closed: %w", ctx.Err())
+		s.mu.Unlock()
+		return false
+	}
+	return ok
+}
+
+// WriteToContext behaves like WriteTo but also unblocks and returns
+// as soon as ctx is done, closing the AuditStream in the process.
+func (s *AuditStream) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	var (
+		n   int64
+		err error
+	)
+	if ctxDone(ctx, s.Close, func() bool { n, err = s.WriteTo(w); return err == nil }) {
+		s.mu.Lock()
+		s.err = fmt.Errorf("kes: audit stream closed: %w", ctx.Err())
+		err = s.err
+		s.mu.Unlock()
+		return n, err
+	}
+	return n, err
+}
+
 // Close closes the AuditStream and releases
 // any associated resources.
-func (s *AuditStream) Close() (err error) {
+func (s *AuditStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
+
+// closeLocked is Close's body, for callers that already hold s.mu.
+func (s *AuditStream) closeLocked() error {
 	if !s.closed {
 		s.closed = true
 
@@ -259,3 +460,30 @@ func (w *countWriter) Write(p []byte) (int, error) {
 	w.N += int64(n)
 	return n, err
 }
+
+// ctxDone runs fn, which may block on I/O, while watching ctx. If ctx
+// is done before fn returns, closeFn is invoked to unblock fn, and
+// ctxDone waits for fn to return before reporting that ctx ended it.
+//
+// fn reports its own success as its return value. ctxDone only
+// attributes fn's failure to ctx if fn itself reports failure -
+// otherwise an already-expired ctx could race a fn that completes
+// successfully anyway, and the caller would wrongly discard a
+// legitimately decoded event.
+func ctxDone(ctx context.Context, closeFn func() error, fn func() bool) bool {
+	stop := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			closeFn()
+		case <-stop:
+		}
+	}()
+
+	ok := fn()
+	close(stop)
+	<-watched
+	return !ok && ctx.Err() != nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestErrorStreamNextContextRace exercises the race NextContext's
+// ctxDone helper is prone to: ctx ending concurrently with Next
+// decoding an event, both of which touch ErrorStream's shared state.
+// Run with `go test -race` to catch a regression.
+func TestErrorStreamNextContextRace(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		pr, pw := io.Pipe()
+		stream := NewErrorStream(pr)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream.NextContext(ctx)
+		}()
+
+		// Give ctx a chance to expire while Next is still blocked
+		// reading from pr, which nothing has been written to yet.
+		time.Sleep(2 * time.Millisecond)
+		pw.Close()
+		wg.Wait()
+		cancel()
+	}
+}
+
+// TestAuditStreamWriteToContextRace is the WriteToContext analogue of
+// TestErrorStreamNextContextRace, run under -race.
+func TestAuditStreamWriteToContextRace(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		pr, pw := io.Pipe()
+		stream := NewAuditStream(pr)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream.WriteToContext(ctx, io.Discard)
+		}()
+
+		time.Sleep(2 * time.Millisecond)
+		pw.Close()
+		wg.Wait()
+		cancel()
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import "context"
+
+// AuditSink receives a copy of each AuditEvent read from an
+// AuditStream, typically to forward it into a SIEM or observability
+// backend. Use Pipe to pump an AuditStream into an AuditSink.
+type AuditSink interface {
+	// Write writes event to the sink.
+	Write(ctx context.Context, event AuditEvent) error
+
+	// Close releases any resources held by the sink.
+	Close() error
+}
+
+// ErrorSink receives a copy of each ErrorEvent read from an
+// ErrorStream. Use Pipe to pump an ErrorStream into an ErrorSink.
+type ErrorSink interface {
+	// Write writes event to the sink.
+	Write(ctx context.Context, event ErrorEvent) error
+
+	// Close releases any resources held by the sink.
+	Close() error
+}
+
+// Pipe reads events from stream, via its NextContext and Event
+// methods, and writes each one to sink until stream is exhausted, ctx
+// is done, or sink returns an error. It always closes sink before
+// returning.
+//
+// Pipe(ctx, auditStream, auditSink) pumps an *AuditStream into an
+// AuditSink; Pipe(ctx, errorStream, errorSink) pumps an *ErrorStream
+// into an ErrorSink.
+func Pipe[T any](ctx context.Context, stream interface {
+	NextContext(ctx context.Context) bool
+	Event() T
+	Close() error
+}, sink interface {
+	Write(ctx context.Context, event T) error
+	Close() error
+},
+) error {
+	defer sink.Close()
+
+	for stream.NextContext(ctx) {
+		if err := sink.Write(ctx, stream.Event()); err != nil {
+			stream.Close()
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return stream.Close()
+}
@@ -0,0 +1,332 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventSource is implemented by stream transports that can report
+// the ID of the last event they successfully delivered.
+type eventSource interface {
+	LastEventID() string
+}
+
+// NewErrorEventSource returns a new ErrorStream that tails url as a
+// Server-Sent Events (SSE) stream. Unlike NewErrorStream, the returned
+// ErrorStream transparently reconnects - with exponential backoff and
+// a Last-Event-ID header - whenever the underlying HTTP response ends,
+// making it suitable for long-lived log tailing over flaky networks.
+func NewErrorEventSource(ctx context.Context, client *http.Client, url string) *ErrorStream {
+	src := newSSESource(ctx, client, url, "")
+	s := NewErrorStream(src)
+	s.source = src
+	return s
+}
+
+// NewAuditEventSource returns a new AuditStream that tails url as a
+// Server-Sent Events (SSE) stream. Unlike NewAuditStream, the returned
+// AuditStream transparently reconnects - with exponential backoff and
+// a Last-Event-ID header - whenever the underlying HTTP response ends,
+// making it suitable for long-lived log tailing over flaky networks.
+func NewAuditEventSource(ctx context.Context, client *http.Client, url string) *AuditStream {
+	src := newSSESource(ctx, client, url, "")
+	s := NewAuditStream(src)
+	s.source = src
+	return s
+}
+
+// NewErrorEventSourceWithCodec behaves like NewErrorEventSource but
+// decodes the tailed stream with newCodec(r) instead of the default
+// NDJSON encoding, and advertises the codec's content type via an
+// Accept header so a KES server that content-negotiates can respond
+// with that encoding.
+func NewErrorEventSourceWithCodec(ctx context.Context, client *http.Client, url string, newCodec func(r io.Reader) Codec) *ErrorStream {
+	accept := newCodec(nil).ContentType()
+	src := newSSESource(ctx, client, url, accept)
+	s := NewErrorStreamWithCodec(newCodec(src), src)
+	s.source = src
+	return s
+}
+
+// NewAuditEventSourceWithCodec behaves like NewAuditEventSource but
+// decodes the tailed stream with newCodec(r) instead of the default
+// NDJSON encoding, and advertises the codec's content type via an
+// Accept header so a KES server that content-negotiates can respond
+// with that encoding.
+func NewAuditEventSourceWithCodec(ctx context.Context, client *http.Client, url string, newCodec func(r io.Reader) Codec) *AuditStream {
+	accept := newCodec(nil).ContentType()
+	src := newSSESource(ctx, client, url, accept)
+	s := NewAuditStreamWithCodec(newCodec(src), src)
+	s.source = src
+	return s
+}
+
+// errFatalSSE wraps errors that must not trigger a reconnect attempt,
+// e.g. a malformed request URL or a 4xx response from the server.
+var errFatalSSE = errors.New("kes: fatal SSE error")
+
+// sseSource is an io.ReadCloser that turns a Server-Sent Events stream
+// at a URL into a continuous stream of newline-delimited JSON - the
+// `data:` payload of each SSE event - so that it can be consumed by
+// the existing json.Decoder based ErrorStream / AuditStream decoding.
+//
+// It reconnects automatically, using the Last-Event-ID header and an
+// exponential backoff, whenever the connection to the server drops.
+type sseSource struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *http.Client
+	url    string
+
+	pr *io.PipeReader
+	pw *io.PipeWriter
+
+	// accept is set once, before the run goroutine starts, and never
+	// written again - safe to read from run/connect without locking.
+	accept string
+
+	mu           sync.Mutex
+	lastEventID  string
+	retry        time.Duration
+	queryFilter  *AuditFilter
+	serverFilter bool
+
+	closeOnce sync.Once
+}
+
+// auditFilterCapabilityHeader is the response header a KES server
+// sets to advertise that it understands the audit filter query
+// parameters applied by ApplyFilter.
+const auditFilterCapabilityHeader = "X-Kes-Audit-Filter"
+
+// SupportsServerFilter reports whether the server has advertised, on
+// a previous connection, that it understands server-side audit
+// filters.
+func (s *sseSource) SupportsServerFilter() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serverFilter
+}
+
+// ApplyFilter configures s to request filter from the server, as
+// query parameters, on its next (re-)connection, provided the server
+// has advertised support for server-side filtering by then.
+func (s *sseSource) ApplyFilter(filter AuditFilter) {
+	s.mu.Lock()
+	s.queryFilter = &filter
+	s.mu.Unlock()
+}
+
+func newSSESource(ctx context.Context, client *http.Client, url string, accept string) *sseSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	s := &sseSource{
+		ctx:    ctx,
+		cancel: cancel,
+		client: client,
+		url:    url,
+		pr:     pr,
+		pw:     pw,
+		accept: accept,
+		retry:  time.Second,
+	}
+	go s.run()
+	return s
+}
+
+func (s *sseSource) Read(p []byte) (int, error) { return s.pr.Read(p) }
+
+func (s *sseSource) Close() error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		s.pw.CloseWithError(io.EOF)
+		s.pr.Close()
+	})
+	return nil
+}
+
+func (s *sseSource) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastEventID
+}
+
+// run repeatedly connects to s.url, re-connecting with an exponential
+// backoff whenever the connection ends, until s.ctx is cancelled or a
+// fatal, non-retryable error occurs.
+func (s *sseSource) run() {
+	defer s.pw.Close()
+
+	attempt := 0
+	for {
+		if s.ctx.Err() != nil {
+			return
+		}
+		err := s.connect()
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err != nil && errors.Is(err, errFatalSSE) {
+			s.pw.CloseWithError(err)
+			return
+		}
+		if err == nil {
+			attempt = 0
+		} else {
+			attempt++
+		}
+
+		select {
+		case <-time.After(s.reconnectDelay(attempt)):
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// reconnectDelay returns the backoff duration before the n-th
+// reconnect attempt, doubling the server-advertised (or default)
+// retry interval up to a 30s ceiling and adding jitter to avoid
+// reconnect storms against the same server.
+func (s *sseSource) reconnectDelay(attempt int) time.Duration {
+	const maxDelay = 30 * time.Second
+
+	s.mu.Lock()
+	delay := s.retry
+	s.mu.Unlock()
+
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// requestURL returns s.url, extended with query parameters encoding
+// the currently configured AuditFilter, if any - but only once the
+// server has advertised, on a previous connection, that it supports
+// server-side audit filtering.
+func (s *sseSource) requestURL() (string, error) {
+	s.mu.Lock()
+	filter := s.queryFilter
+	serverFilter := s.serverFilter
+	s.mu.Unlock()
+	if filter == nil || !serverFilter {
+		return s.url, nil
+	}
+
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if filter.APIPathGlob != "" {
+		q.Set("path", filter.APIPathGlob)
+	}
+	if filter.MinStatus != 0 {
+		q.Set("min-status", strconv.Itoa(filter.MinStatus))
+	}
+	if filter.ClientIdentity != "" {
+		q.Set("identity", string(filter.ClientIdentity))
+	}
+	if !filter.Since.IsZero() {
+		q.Set("since", filter.Since.UTC().Format(time.RFC3339))
+	}
+	if filter.MinResponseTime != 0 {
+		q.Set("min-response-time", filter.MinResponseTime.String())
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// connect performs a single SSE request and streams decoded `data:`
+// payloads into s.pw until the response body ends or an error occurs.
+func (s *sseSource) connect() error {
+	reqURL, err := s.requestURL()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errFatalSSE, err)
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errFatalSSE, err)
+	}
+	if s.accept != "" {
+		req.Header.Set("Accept", s.accept)
+	} else {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	if id := s.LastEventID(); id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return fmt.Errorf("%w: server returned %s", errFatalSSE, resp.Status)
+		}
+		return fmt.Errorf("kes: server returned %s", resp.Status)
+	}
+
+	s.mu.Lock()
+	s.serverFilter = resp.Header.Get(auditFilterCapabilityHeader) != ""
+	s.mu.Unlock()
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if _, err := io.WriteString(s.pw, data.String()+"\n"); err != nil {
+					return err
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive frame - ignore.
+		case strings.HasPrefix(line, "id:"):
+			s.mu.Lock()
+			s.lastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			s.mu.Unlock()
+		case strings.HasPrefix(line, "retry:"):
+			if ms, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "retry:"))); err == nil {
+				s.mu.Lock()
+				s.retry = time.Duration(ms) * time.Millisecond
+				s.mu.Unlock()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReconnectDelayBounds(t *testing.T) {
+	const maxDelay = 30 * time.Second
+
+	s := &sseSource{retry: 500 * time.Millisecond}
+	for _, attempt := range []int{0, 1, 5, 10, 100} {
+		for i := 0; i < 10; i++ {
+			d := s.reconnectDelay(attempt)
+			if d <= 0 {
+				t.Fatalf("attempt %d: non-positive delay %v", attempt, d)
+			}
+			if d > maxDelay+maxDelay/2 {
+				t.Fatalf("attempt %d: delay %v exceeds the capped base plus its jitter ceiling", attempt, d)
+			}
+		}
+	}
+}
+
+func TestReconnectDelayCapsAtMaxDelay(t *testing.T) {
+	const maxDelay = 30 * time.Second
+
+	s := &sseSource{retry: time.Second}
+	// Enough doublings to blow past maxDelay regardless of the
+	// starting retry interval.
+	if d := s.reconnectDelay(100); d < maxDelay {
+		t.Fatalf("delay %v is below the maxDelay floor once the base has capped", d)
+	}
+}
+
+// TestNewErrorEventSourceWithCodecSendsAcceptHeader checks that the
+// codec's content type reaches the server as an Accept header - and,
+// since it's read from newSSESource's accept field before the source's
+// run goroutine starts, that it's there on the very first request.
+func TestNewErrorEventSourceWithCodecSendsAcceptHeader(t *testing.T) {
+	acceptCh := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case acceptCh <- r.Header.Get("Accept"):
+		default:
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := NewErrorEventSourceWithCodec(ctx, srv.Client(), srv.URL, func(r io.Reader) Codec {
+		return NewCBORCodec(r, nil)
+	})
+	defer stream.Close()
+
+	select {
+	case got := <-acceptCh:
+		if want := NewCBORCodec(nil, nil).ContentType(); got != want {
+			t.Errorf("Accept header = %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server never saw a request")
+	}
+}